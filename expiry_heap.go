@@ -0,0 +1,45 @@
+package timedmap
+
+import "container/heap"
+
+// heapEntry tracks a key's expiry time in a Map's expiryHeap. It may go
+// stale if the key is re-Set or Extended after the entry was pushed; entries
+// are validated against the map's container before being acted on.
+type heapEntry[K comparable, V any] struct {
+	key     K
+	elem    *Element[V]
+	timeSec int64 // unixnano, mirrors elem.expires at push time
+}
+
+// expiryHeap is a container/heap.Interface of heapEntry ordered by timeSec,
+// letting Cleanup find expired keys in O(k log n) instead of scanning the
+// whole map.
+type expiryHeap[K comparable, V any] []*heapEntry[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].timeSec < h[j].timeSec }
+
+func (h expiryHeap[K, V]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap[K, V]) Push(x interface{}) {
+	*h = append(*h, x.(*heapEntry[K, V]))
+}
+
+func (h *expiryHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// pushExpiry records key's current expiry in the heap. Callers must hold tm.mtx.
+func (tm *Map[K, V]) pushExpiry(key K, elem *Element[V]) {
+	heap.Push(&tm.expiry, &heapEntry[K, V]{
+		key:     key,
+		elem:    elem,
+		timeSec: elem.Expires(),
+	})
+}
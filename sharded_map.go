@@ -0,0 +1,182 @@
+package timedmap
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"time"
+)
+
+// Hasher computes a shard-selection hash for a key of type K.
+type Hasher[K comparable] func(key K) uint64
+
+// StringHasher hashes string keys with FNV-1a.
+func StringHasher(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// BytesHasher hashes []byte keys with FNV-1a.
+func BytesHasher(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	return h.Sum64()
+}
+
+// ReflectHasher hashes an arbitrary comparable key by hashing its reflect
+// representation. It works for any comparable K, but is slower than
+// StringHasher or BytesHasher, so prefer those for string/[]byte keys.
+func ReflectHasher[K comparable](key K) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%#v", key)
+	return h.Sum64()
+}
+
+// ShardedMap splits its keyspace across a fixed number of independent Maps,
+// each with its own lock, to reduce the single-mutex contention a Map sees
+// under concurrent access.
+type ShardedMap[K comparable, V any] struct {
+	shards []*Map[K, V]
+	hasher Hasher[K]
+	mask   uint64
+}
+
+var _ Cleanable = (*ShardedMap[string, any])(nil)
+
+// AnyShardedMap is a ShardedMap keyed and valued by interface{}, matching
+// AnyMap's role for the unsharded Map.
+type AnyShardedMap = ShardedMap[any, any]
+
+// NewSharded creates a ShardedMap with the given number of shards, rounded
+// up to the next power of two. If shards <= 0, runtime.GOMAXPROCS(0) is used
+// instead. Keys are routed to shards with hasher; use StringHasher,
+// BytesHasher, or ReflectHasher unless the key type warrants a custom one.
+func NewSharded[K comparable, V any](shards int, hasher Hasher[K]) *ShardedMap[K, V] {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	shards = nextPowerOfTwo(shards)
+
+	sm := &ShardedMap[K, V]{
+		shards: make([]*Map[K, V], shards),
+		hasher: hasher,
+		mask:   uint64(shards - 1),
+	}
+	for i := range sm.shards {
+		sm.shards[i] = New[K, V]()
+	}
+	return sm
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+func (sm *ShardedMap[K, V]) shardFor(key K) *Map[K, V] {
+	return sm.shards[sm.hasher(key)&sm.mask]
+}
+
+// Set appends a key-value pair to the map or sets the value of
+// a key. expiresAfter sets the expire time after the key-value pair
+// will automatically be removed from the map.
+func (sm *ShardedMap[K, V]) Set(key K, value V, expiresAfter time.Duration) {
+	sm.shardFor(key).Set(key, value, expiresAfter)
+}
+
+// SetWithCallback is like Set, but additionally registers a callback that is
+// invoked when the key-value pair is evicted.
+func (sm *ShardedMap[K, V]) SetWithCallback(key K, value V, expiresAfter time.Duration, onEvict func()) {
+	sm.shardFor(key).SetWithCallback(key, value, expiresAfter, onEvict)
+}
+
+// SetOnEvicted sets a callback invoked whenever any key-value pair leaves
+// any shard, whether through expiry or manual removal. Pass nil to remove
+// it.
+func (sm *ShardedMap[K, V]) SetOnEvicted(onEvicted func(key K, value V)) {
+	for _, shard := range sm.shards {
+		shard.SetOnEvicted(onEvicted)
+	}
+}
+
+// Get returns an element object by key.
+func (sm *ShardedMap[K, V]) Get(key K) (*Element[V], bool) {
+	return sm.shardFor(key).Get(key)
+}
+
+// GetValue returns the value of a key in the map. The returned bool is false
+// if there is no value to the passed key or if the value was expired.
+func (sm *ShardedMap[K, V]) GetValue(key K) (V, bool) {
+	return sm.shardFor(key).GetValue(key)
+}
+
+// GetExpires returns the expire time of a key-value pair. If the key-value pair
+// does not exist in the map or was expired, this will return false.
+func (sm *ShardedMap[K, V]) GetExpires(key K) (time.Time, bool) {
+	return sm.shardFor(key).GetExpires(key)
+}
+
+// Contains returns true, if the key exists in the map.
+// false will be returned, if there is no value to the
+// key or if the key-value pair was expired.
+func (sm *ShardedMap[K, V]) Contains(key K) bool {
+	return sm.shardFor(key).Contains(key)
+}
+
+// Remove deletes a key-value pair in the map.
+func (sm *ShardedMap[K, V]) Remove(key K) {
+	sm.shardFor(key).Remove(key)
+}
+
+// Extend adds the duration into the expiry time.
+func (sm *ShardedMap[K, V]) Extend(key K, d time.Duration) bool {
+	return sm.shardFor(key).Extend(key, d)
+}
+
+// Add is like Set, but fails with ErrKeyExists if key already exists with
+// an unexpired value.
+func (sm *ShardedMap[K, V]) Add(key K, value V, expiresAfter time.Duration) error {
+	return sm.shardFor(key).Add(key, value, expiresAfter)
+}
+
+// Replace is like Set, but fails with ErrKeyNotFound if key does not exist
+// with an unexpired value.
+func (sm *ShardedMap[K, V]) Replace(key K, value V, expiresAfter time.Duration) error {
+	return sm.shardFor(key).Replace(key, value, expiresAfter)
+}
+
+// GetOrSet returns the current unexpired value of key, if any, otherwise it
+// sets key to value and returns value. See Map.GetOrSet for details.
+func (sm *ShardedMap[K, V]) GetOrSet(key K, value V, expiresAfter time.Duration) (actual V, loaded bool) {
+	return sm.shardFor(key).GetOrSet(key, value, expiresAfter)
+}
+
+// Flush deletes all key-value pairs of the map.
+func (sm *ShardedMap[K, V]) Flush() {
+	for _, shard := range sm.shards {
+		shard.Flush()
+	}
+}
+
+// Size returns the current number of key-value pairs
+// existent in the map.
+func (sm *ShardedMap[K, V]) Size() int {
+	n := 0
+	for _, shard := range sm.shards {
+		n += shard.Size()
+	}
+	return n
+}
+
+// Cleanup expires all key-value pairs which have passed their expiry time.
+// Shards are cleaned up one at a time, each under its own lock, so a slow
+// shard cleanup does not block reads or writes on the others.
+func (sm *ShardedMap[K, V]) Cleanup() {
+	for _, shard := range sm.shards {
+		shard.Cleanup()
+	}
+}
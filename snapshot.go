@@ -0,0 +1,109 @@
+package timedmap
+
+import (
+	"encoding/gob"
+	"io"
+	"os"
+	"time"
+)
+
+// Register forwards to gob.Register, making value's concrete type known to
+// encoding/gob. It must be called once per concrete type before Save or
+// Load is used on a Map whose K or V is itself an interface type, such as
+// AnyMap.
+func Register(value interface{}) {
+	gob.Register(value)
+}
+
+// snapshotEntry is the gob-encoded form of one Map entry.
+type snapshotEntry[K comparable, V any] struct {
+	Key      K
+	Value    V
+	Expires  int64 // unixnano
+	NoExpire bool
+}
+
+// Save writes tm's keys, values, and absolute expiry times to w using
+// encoding/gob, so a process can persist its cache on shutdown and
+// rehydrate it with Load on startup without a cold-cache latency spike.
+func (tm *Map[K, V]) Save(w io.Writer) error {
+	tm.mtx.RLock()
+	entries := make([]snapshotEntry[K, V], 0, len(tm.container))
+	for key, elem := range tm.container {
+		entries = append(entries, snapshotEntry[K, V]{
+			Key:      key,
+			Value:    elem.Value,
+			Expires:  elem.Expires(),
+			NoExpire: elem.noExpire,
+		})
+	}
+	tm.mtx.RUnlock()
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// SaveFile is like Save, but writes to the file at path, creating or
+// truncating it as needed.
+func (tm *Map[K, V]) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return tm.Save(f)
+}
+
+// Load reads entries written by Save from r and merges them into tm,
+// keeping tm's existing entries whose keys are not in the snapshot; a saved
+// entry replaces an existing entry with the same key. Entries whose expiry
+// has already passed are dropped. Use LoadReplace to discard tm's existing entries instead of
+// merging.
+func (tm *Map[K, V]) Load(r io.Reader) error {
+	var entries []snapshotEntry[K, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	for _, entry := range entries {
+		if !entry.NoExpire && now > entry.Expires {
+			continue
+		}
+
+		elem := &Element[V]{
+			Value:    entry.Value,
+			expires:  entry.Expires,
+			noExpire: entry.NoExpire,
+		}
+		tm.container[entry.Key] = elem
+		if !elem.noExpire {
+			tm.pushExpiry(entry.Key, elem)
+		}
+	}
+
+	return nil
+}
+
+// LoadFile is like Load, but reads from the file at path.
+func (tm *Map[K, V]) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return tm.Load(f)
+}
+
+// LoadReplace is like Load, but flushes tm before loading, so tm ends up
+// containing exactly the saved entries instead of a merge with whatever it
+// held before.
+func (tm *Map[K, V]) LoadReplace(r io.Reader) error {
+	tm.Flush()
+	return tm.Load(r)
+}
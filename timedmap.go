@@ -1,75 +1,163 @@
 package timedmap
 
 import (
+	"container/heap"
+	"errors"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// Map contains a map with all key-value pairs. It does not automatically clean
-// up.
-type Map struct {
-	mtx       sync.RWMutex
-	container map[interface{}]*Element
-}
+const (
+	// NoExpiration, passed as expiresAfter, stores an item forever: it is
+	// never evicted by Cleanup and never reported as expired by Get.
+	NoExpiration time.Duration = -1
+
+	// DefaultExpiration, passed as expiresAfter, resolves to the Map's
+	// configured default TTL, set via NewWithDefault. On a Map created
+	// with New, no default is configured, so it behaves like
+	// NoExpiration.
+	DefaultExpiration time.Duration = 0
+)
 
-var _ Cleanable = (*Map)(nil)
+var (
+	// ErrKeyExists is returned by Add when the key already exists and has
+	// not expired.
+	ErrKeyExists = errors.New("timedmap: key exists")
 
-// Element contains the actual value as interface type and the time when the
-// value expires.
-type Element struct {
-	Value   interface{}
-	expires int64 // unixnano
+	// ErrKeyNotFound is returned by Replace, Increment, and Decrement when
+	// the key does not exist or has already expired.
+	ErrKeyNotFound = errors.New("timedmap: key not found")
+)
+
+// Map contains a map with all key-value pairs. It does not automatically
+// clean up.
+type Map[K comparable, V any] struct {
+	mtx        sync.RWMutex
+	container  map[K]*Element[V]
+	expiry     expiryHeap[K, V]
+	onEvicted  func(key K, value V)
+	defaultTTL time.Duration
 }
 
-// something something low allocations
-var nilElement = Element{}
+var _ Cleanable = (*Map[string, any])(nil)
+
+// AnyMap is a Map keyed and valued by interface{}, matching the pre-generics
+// API. It exists as a migration target for callers that are not yet ready to
+// parameterize their maps.
+type AnyMap = Map[any, any]
+
+// Element contains the actual value and the time when the value expires.
+type Element[V any] struct {
+	Value    V
+	expires  int64 // unixnano
+	noExpire bool
+	onEvict  func()
+}
 
 // Expires returns the expiry time in UnixNano. This method is thread-safe.
-func (e *Element) Expires() int64 {
+func (e *Element[V]) Expires() int64 {
 	return atomic.LoadInt64(&e.expires)
 }
 
 // ExpiryTime returns the expiry time in time.Time. This method is thread-safe.
-func (e *Element) ExpiryTime() time.Time {
+func (e *Element[V]) ExpiryTime() time.Time {
 	return time.Unix(0, e.Expires())
 }
 
 // New creates and returns a new instance of Map. This Map does not
-// periodically clean up.
-func New() *Map {
-	return &Map{
-		container: make(map[interface{}]*Element),
+// periodically clean up. Set calls using DefaultExpiration behave like
+// NoExpiration, since no default TTL is configured; use NewWithDefault to
+// configure one.
+func New[K comparable, V any]() *Map[K, V] {
+	return NewWithDefault[K, V](NoExpiration)
+}
+
+// NewWithDefault is like New, but Set calls using DefaultExpiration resolve
+// to defaultTTL instead.
+func NewWithDefault[K comparable, V any](defaultTTL time.Duration) *Map[K, V] {
+	return &Map[K, V]{
+		container:  make(map[K]*Element[V]),
+		defaultTTL: defaultTTL,
 	}
 }
 
+// elementLive reports whether elem has not yet expired.
+func elementLive[V any](elem *Element[V]) bool {
+	return elem.noExpire || time.Now().UnixNano() <= elem.Expires()
+}
+
 // Set appends a key-value pair to the map or sets the value of
 // a key. expiresAfter sets the expire time after the key-value pair
 // will automatically be removed from the map.
-func (tm *Map) Set(key, value interface{}, expiresAfter time.Duration) {
+func (tm *Map[K, V]) Set(key K, value V, expiresAfter time.Duration) {
 	tm.mtx.Lock()
 	defer tm.mtx.Unlock()
 
-	tm.container[key] = &Element{
-		Value:   value,
-		expires: time.Now().Add(expiresAfter).UnixNano(),
+	tm.setLocked(key, value, expiresAfter, nil)
+}
+
+// SetWithCallback is like Set, but additionally registers a callback that is
+// invoked when the key-value pair is evicted, whether through expiry or a
+// manual Remove/Flush. It runs outside of tm's lock, so it may safely call
+// back into tm.
+func (tm *Map[K, V]) SetWithCallback(key K, value V, expiresAfter time.Duration, onEvict func()) {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	tm.setLocked(key, value, expiresAfter, onEvict)
+}
+
+// setLocked stores value for key with the given TTL, resolving
+// DefaultExpiration/NoExpiration and pushing a heap entry if the element can
+// expire. Callers must hold tm.mtx for writing.
+func (tm *Map[K, V]) setLocked(key K, value V, expiresAfter time.Duration, onEvict func()) {
+	if expiresAfter == DefaultExpiration {
+		expiresAfter = tm.defaultTTL
+	}
+
+	elem := &Element[V]{
+		Value:    value,
+		onEvict:  onEvict,
+		noExpire: expiresAfter == NoExpiration,
 	}
+	if elem.noExpire {
+		elem.expires = math.MaxInt64
+	} else {
+		elem.expires = time.Now().Add(expiresAfter).UnixNano()
+	}
+
+	tm.container[key] = elem
+	if !elem.noExpire {
+		tm.pushExpiry(key, elem)
+	}
+}
+
+// SetOnEvicted sets a callback invoked whenever any key-value pair leaves
+// the map, whether through expiry (Cleanup) or manual removal (Remove,
+// Flush). It runs outside of tm's lock, so the callback may safely call
+// back into tm. Pass nil to remove it.
+func (tm *Map[K, V]) SetOnEvicted(onEvicted func(key K, value V)) {
+	tm.mtx.Lock()
+	tm.onEvicted = onEvicted
+	tm.mtx.Unlock()
 }
 
-// GetValue returns an interface of the value of a key in the map. The returned
-// value is nil if there is no value to the passed key or if the value was
-// expired.
-func (tm *Map) GetValue(key interface{}) interface{} {
+// GetValue returns the value of a key in the map. The returned bool is false
+// if there is no value to the passed key or if the value was expired.
+func (tm *Map[K, V]) GetValue(key K) (V, bool) {
 	v, ok := tm.Get(key)
 	if ok {
-		return v.Value
+		return v.Value, true
 	}
-	return nil
+	var zero V
+	return zero, false
 }
 
 // GetExpires returns the expire time of a key-value pair. If the key-value pair
 // does not exist in the map or was expired, this will return false.
-func (tm *Map) GetExpires(key interface{}) (time.Time, bool) {
+func (tm *Map[K, V]) GetExpires(key K) (time.Time, bool) {
 	v, ok := tm.Get(key)
 	if ok {
 		return v.ExpiryTime(), true
@@ -80,70 +168,207 @@ func (tm *Map) GetExpires(key interface{}) (time.Time, bool) {
 // Contains returns true, if the key exists in the map.
 // false will be returned, if there is no value to the
 // key or if the key-value pair was expired.
-func (tm *Map) Contains(key interface{}) bool {
+func (tm *Map[K, V]) Contains(key K) bool {
 	_, ok := tm.Get(key)
 	return ok
 }
 
 // Remove deletes a key-value pair in the map.
-func (tm *Map) Remove(key interface{}) {
+func (tm *Map[K, V]) Remove(key K) {
 	tm.mtx.Lock()
+	elem, ok := tm.container[key]
 	delete(tm.container, key)
+	onEvicted := tm.onEvicted
 	tm.mtx.Unlock()
+
+	if !ok {
+		return
+	}
+	if elem.onEvict != nil {
+		elem.onEvict()
+	}
+	if onEvicted != nil {
+		onEvicted(key, elem.Value)
+	}
 }
 
 // Extend adds the duration into the expiry time.
-func (tm *Map) Extend(key interface{}, d time.Duration) bool {
-	v, ok := tm.Get(key)
-	if ok {
-		atomic.AddInt64(&v.expires, int64(d))
+func (tm *Map[K, V]) Extend(key K, d time.Duration) bool {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	elem, ok := tm.container[key]
+	if !ok || !elementLive(elem) {
+		return false
 	}
-	return ok
+	if elem.noExpire {
+		return true
+	}
+
+	atomic.AddInt64(&elem.expires, int64(d))
+	// The heap entry pushed in Set is now stale; push a fresh one so
+	// Cleanup still finds the new expiry. Cleanup ignores stale entries.
+	tm.pushExpiry(key, elem)
+	return true
 }
 
 // Flush deletes all key-value pairs of the map.
-func (tm *Map) Flush() {
+func (tm *Map[K, V]) Flush() {
 	tm.mtx.Lock()
-	defer tm.mtx.Unlock()
+	old := tm.container
+	tm.container = make(map[K]*Element[V])
+	tm.expiry = nil
+	onEvicted := tm.onEvicted
+	tm.mtx.Unlock()
 
-	tm.container = make(map[interface{}]*Element)
+	for key, elem := range old {
+		if elem.onEvict != nil {
+			elem.onEvict()
+		}
+		if onEvicted != nil {
+			onEvicted(key, elem.Value)
+		}
+	}
 }
 
 // Size returns the current number of key-value pairs
 // existent in the map.
-func (tm *Map) Size() int {
+func (tm *Map[K, V]) Size() int {
 	tm.mtx.RLock()
 	defer tm.mtx.RUnlock()
 
 	return len(tm.container)
 }
 
-// cleanUp iterates trhough the map and expires all key-value
-// pairs which expire time after the current time
-func (tm *Map) Cleanup() {
+// Cleanup pops expired entries off the expiry heap and deletes them from the
+// map, which costs O(k log n) for k expiring entries instead of a full O(n)
+// scan of the map.
+func (tm *Map[K, V]) Cleanup() {
 	tm.mtx.Lock()
-	defer tm.mtx.Unlock()
 
 	// getting now after mutex to prevent drifting
 	now := time.Now().UnixNano()
 
-	for k, v := range tm.container {
-		if now > v.expires {
-			delete(tm.container, k)
+	var evicted []*Element[V]
+	var evictedKeys []K
+
+	for len(tm.expiry) > 0 && now > tm.expiry[0].timeSec {
+		entry := heap.Pop(&tm.expiry).(*heapEntry[K, V])
+
+		// The key may have been re-Set, Extended, or Removed since this
+		// entry was pushed; only act on it if it still reflects the
+		// element currently stored for key.
+		current, ok := tm.container[entry.key]
+		if !ok || current != entry.elem || current.Expires() != entry.timeSec {
+			continue
+		}
+
+		delete(tm.container, entry.key)
+		evicted = append(evicted, current)
+		evictedKeys = append(evictedKeys, entry.key)
+	}
+
+	onEvicted := tm.onEvicted
+	tm.mtx.Unlock()
+
+	for i, elem := range evicted {
+		if elem.onEvict != nil {
+			elem.onEvict()
+		}
+		if onEvicted != nil {
+			onEvicted(evictedKeys[i], elem.Value)
 		}
 	}
 }
 
 // Get returns an element object by key.
-func (tm *Map) Get(key interface{}) (*Element, bool) {
+func (tm *Map[K, V]) Get(key K) (*Element[V], bool) {
 	tm.mtx.RLock()
 	v, ok := tm.container[key]
 	tm.mtx.RUnlock()
 
 	// let the cleaner do the job.
-	if !ok || time.Now().UnixNano() > v.Expires() {
+	if !ok || !elementLive(v) {
 		return nil, false
 	}
 
 	return v, true
 }
+
+// Add sets the value of key to value and gives it expiresAfter to live, but
+// only if key does not already exist with an unexpired value. If it does,
+// Add returns ErrKeyExists and leaves the map unchanged.
+func (tm *Map[K, V]) Add(key K, value V, expiresAfter time.Duration) error {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	if elem, ok := tm.container[key]; ok && elementLive(elem) {
+		return ErrKeyExists
+	}
+
+	tm.setLocked(key, value, expiresAfter, nil)
+	return nil
+}
+
+// Replace sets the value of key to value and gives it expiresAfter to live,
+// but only if key already exists with an unexpired value. If it does not,
+// Replace returns ErrKeyNotFound and leaves the map unchanged.
+func (tm *Map[K, V]) Replace(key K, value V, expiresAfter time.Duration) error {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	elem, ok := tm.container[key]
+	if !ok || !elementLive(elem) {
+		return ErrKeyNotFound
+	}
+
+	tm.setLocked(key, value, expiresAfter, nil)
+	return nil
+}
+
+// GetOrSet returns the current unexpired value of key, if any. Otherwise, it
+// sets key to value with expiresAfter to live and returns value. The check
+// and the set happen atomically under tm's write lock, so GetOrSet is safe
+// to use for check-then-set logic without a caller-side race.
+func (tm *Map[K, V]) GetOrSet(key K, value V, expiresAfter time.Duration) (actual V, loaded bool) {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	if elem, ok := tm.container[key]; ok && elementLive(elem) {
+		return elem.Value, true
+	}
+
+	tm.setLocked(key, value, expiresAfter, nil)
+	return value, false
+}
+
+// Number is the set of types Increment and Decrement can operate on.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Increment adds n to the value stored at key and returns the new value.
+// It returns ErrKeyNotFound if key does not exist or has expired. Since Go
+// methods cannot introduce new type parameters, Increment is a free
+// function taking the Map rather than a method on it.
+func Increment[K comparable, N Number](tm *Map[K, N], key K, n N) (N, error) {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	elem, ok := tm.container[key]
+	if !ok || !elementLive(elem) {
+		var zero N
+		return zero, ErrKeyNotFound
+	}
+
+	elem.Value += n
+	return elem.Value, nil
+}
+
+// Decrement subtracts n from the value stored at key and returns the new
+// value. It returns ErrKeyNotFound if key does not exist or has expired.
+func Decrement[K comparable, N Number](tm *Map[K, N], key K, n N) (N, error) {
+	return Increment(tm, key, -n)
+}
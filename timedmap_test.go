@@ -1,22 +1,25 @@
 package timedmap
 
 import (
+	"bytes"
 	"runtime"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func BenchmarkMap(b *testing.B) {
-	tm := New()
+	tm := New[string, string]()
 
 	for i := 0; i < b.N; i++ {
 		tm.Set("hime", "arikawa", 99999999999999)
-		_ = tm.GetValue("hime").(string)
+		_, _ = tm.GetValue("hime")
 	}
 }
 
 func BenchmarkConcurrentRead(b *testing.B) {
-	tm := New()
+	tm := New[string, string]()
 	tm.Set("hime", "arikawa", 99999999999)
 
 	b.SetParallelism(runtime.NumCPU() * 2)
@@ -28,7 +31,7 @@ func BenchmarkConcurrentRead(b *testing.B) {
 }
 
 func BenchmarkConcurrentWrite(b *testing.B) {
-	tm := New()
+	tm := New[string, string]()
 
 	b.SetParallelism(runtime.NumCPU() * 2)
 	b.RunParallel(func(pb *testing.PB) {
@@ -38,10 +41,44 @@ func BenchmarkConcurrentWrite(b *testing.B) {
 	})
 }
 
+// BenchmarkCleanupLargeMap demonstrates that Cleanup's cost no longer tracks
+// the size of the map: with nothing expiring, the heap's root is always
+// unexpired, so each call is O(1) rather than scanning all n entries.
+func BenchmarkCleanupLargeMap(b *testing.B) {
+	const n = 100000
+
+	tm := New[int, int]()
+	for i := 0; i < n; i++ {
+		tm.Set(i, i, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm.Cleanup()
+	}
+}
+
+// BenchmarkConcurrentWriteSharded is the ShardedMap counterpart to
+// BenchmarkConcurrentWrite, giving each parallel worker its own key so
+// writes spread across shards instead of contending on one Map's mutex.
+func BenchmarkConcurrentWriteSharded(b *testing.B) {
+	sm := NewSharded[string, string](0, StringHasher)
+
+	var worker int64
+
+	b.SetParallelism(runtime.NumCPU() * 2)
+	b.RunParallel(func(pb *testing.PB) {
+		key := strconv.FormatInt(atomic.AddInt64(&worker, 1), 10)
+		for pb.Next() {
+			sm.Set(key, "arikawa", 99999999999)
+		}
+	})
+}
+
 const cleanupTick = 10 * time.Millisecond
 
-func newTmap(t *testing.T) *Map {
-	tm := New()
+func newTmap(t *testing.T) *AnyMap {
+	tm := New[any, any]()
 	cl := NewCleaner(cleanupTick)
 	cl.AddCleanable(tm)
 	t.Cleanup(cl.Stop)
@@ -67,7 +104,7 @@ func TestSet(t *testing.T) {
 	val := "tValSet"
 
 	tm.Set(key, val, 20*time.Millisecond)
-	vl, ok := tm.get(key)
+	vl, ok := tm.Get(key)
 	if !ok {
 		t.Fatal("key was not set")
 	}
@@ -78,7 +115,7 @@ func TestSet(t *testing.T) {
 
 	time.Sleep(20*time.Millisecond + cleanupTick)
 
-	if v := tm.GetValue(key); v != nil {
+	if _, ok := tm.GetValue(key); ok {
 		t.Fatal("key was not deleted after expire")
 	}
 
@@ -93,12 +130,12 @@ func TestGetValue(t *testing.T) {
 
 	tm.Set(key, val, 50*time.Millisecond)
 
-	if tm.GetValue("keyNotExists") != nil {
+	if _, ok := tm.GetValue("keyNotExists"); ok {
 		t.Fatal("non existent key was not nil")
 	}
 
-	v := tm.GetValue(key)
-	if v == nil {
+	v, ok := tm.GetValue(key)
+	if !ok {
 		t.Fatal("value was nil")
 	}
 	if vStr := v.(string); vStr != val {
@@ -107,8 +144,7 @@ func TestGetValue(t *testing.T) {
 
 	time.Sleep(60 * time.Millisecond)
 
-	v = tm.GetValue(key)
-	if v != nil {
+	if _, ok := tm.GetValue(key); ok {
 		t.Fatal("key was not deleted after expiration time")
 	}
 
@@ -116,7 +152,7 @@ func TestGetValue(t *testing.T) {
 
 	time.Sleep(2 * time.Millisecond)
 
-	if tm.GetValue(key) != nil {
+	if _, ok := tm.GetValue(key); ok {
 		t.Fatal("expired key was not removed by get func")
 	}
 
@@ -175,7 +211,7 @@ func TestRemove(t *testing.T) {
 	tm.Set(key, 1, time.Hour)
 	tm.Remove(key)
 
-	if _, ok := tm.get(key); ok {
+	if _, ok := tm.Get(key); ok {
 		t.Fatal("key still exists after remove")
 	}
 
@@ -199,19 +235,245 @@ func TestExtend(t *testing.T) {
 
 	time.Sleep(30 * time.Millisecond)
 
-	if v := tm.GetValue(key); v == nil {
+	if _, ok := tm.GetValue(key); !ok {
 		t.Fatal("Key was not extended.")
 	}
 
 	time.Sleep(20*time.Millisecond + cleanupTick)
 
-	if _, ok := tm.get(key); ok {
+	if _, ok := tm.Get(key); ok {
 		t.Fatal("key was not deleted after refreshed time")
 	}
 
 	tm.Flush()
 }
 
+func TestOnEvicted(t *testing.T) {
+	tm := newTmap(t)
+
+	key := "tKeyEvict"
+	val := "tValEvict"
+
+	var got []string
+	tm.SetOnEvicted(func(key, value interface{}) {
+		got = append(got, key.(string)+"="+value.(string))
+	})
+
+	tm.Set(key, val, 20*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	tm.Cleanup()
+
+	if len(got) != 1 || got[0] != key+"="+val {
+		t.Fatalf("OnEvicted not called for expiry, got %v", got)
+	}
+
+	tm.Set(key, val, time.Hour)
+	tm.Remove(key)
+
+	if len(got) != 2 || got[1] != key+"="+val {
+		t.Fatalf("OnEvicted not called for Remove, got %v", got)
+	}
+}
+
+func TestSetWithCallback(t *testing.T) {
+	tm := newTmap(t)
+
+	key := "tKeyCb"
+	called := make(chan struct{}, 1)
+
+	tm.SetWithCallback(key, "val", 20*time.Millisecond, func() {
+		called <- struct{}{}
+	})
+
+	select {
+	case <-called:
+		t.Fatal("callback fired before expiry")
+	default:
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	tm.Cleanup()
+
+	select {
+	case <-called:
+	default:
+		t.Fatal("callback was not called on expiry")
+	}
+}
+
+func TestNoExpiration(t *testing.T) {
+	tm := newTmap(t)
+
+	key := "tKeyNoExp"
+
+	tm.Set(key, "val", NoExpiration)
+	time.Sleep(2 * cleanupTick)
+
+	if _, ok := tm.GetValue(key); !ok {
+		t.Fatal("key with NoExpiration was evicted")
+	}
+
+	tm.Flush()
+}
+
+func TestDefaultExpiration(t *testing.T) {
+	tm := New[string, string]()
+	tm2 := NewWithDefault[string, string](20 * time.Millisecond)
+
+	cl := NewCleaner(cleanupTick)
+	cl.AddCleanable(tm)
+	cl.AddCleanable(tm2)
+	t.Cleanup(cl.Stop)
+
+	key := "tKeyDefExp"
+
+	tm.Set(key, "val", DefaultExpiration)
+	time.Sleep(2 * cleanupTick)
+	if _, ok := tm.GetValue(key); !ok {
+		t.Fatal("DefaultExpiration without NewWithDefault did not behave like NoExpiration")
+	}
+
+	tm2.Set(key, "val", DefaultExpiration)
+	time.Sleep(20*time.Millisecond + cleanupTick)
+	if _, ok := tm2.GetValue(key); ok {
+		t.Fatal("DefaultExpiration did not use the configured default TTL")
+	}
+}
+
+func TestAddReplace(t *testing.T) {
+	tm := newTmap(t)
+
+	key := "tKeyAddRep"
+
+	if err := tm.Replace(key, "val", time.Hour); err != ErrKeyNotFound {
+		t.Fatalf("Replace on missing key returned %v, want ErrKeyNotFound", err)
+	}
+
+	if err := tm.Add(key, "val1", time.Hour); err != nil {
+		t.Fatalf("Add on new key returned %v, want nil", err)
+	}
+
+	if err := tm.Add(key, "val2", time.Hour); err != ErrKeyExists {
+		t.Fatalf("Add on existing key returned %v, want ErrKeyExists", err)
+	}
+
+	if err := tm.Replace(key, "val3", time.Hour); err != nil {
+		t.Fatalf("Replace on existing key returned %v, want nil", err)
+	}
+
+	if v, _ := tm.GetValue(key); v.(string) != "val3" {
+		t.Fatalf("value was %v, want val3", v)
+	}
+
+	tm.Flush()
+}
+
+func TestGetOrSet(t *testing.T) {
+	tm := newTmap(t)
+
+	key := "tKeyGetOrSet"
+
+	actual, loaded := tm.GetOrSet(key, "val1", time.Hour)
+	if loaded {
+		t.Fatal("GetOrSet reported loaded for a missing key")
+	}
+	if actual.(string) != "val1" {
+		t.Fatalf("actual was %v, want val1", actual)
+	}
+
+	actual, loaded = tm.GetOrSet(key, "val2", time.Hour)
+	if !loaded {
+		t.Fatal("GetOrSet did not report loaded for an existing key")
+	}
+	if actual.(string) != "val1" {
+		t.Fatalf("actual was %v, want val1", actual)
+	}
+
+	tm.Flush()
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	tm := New[string, int64]()
+	t.Cleanup(tm.Flush)
+
+	key := "tKeyIncr"
+
+	if _, err := Increment(tm, key, 1); err != ErrKeyNotFound {
+		t.Fatalf("Increment on missing key returned %v, want ErrKeyNotFound", err)
+	}
+
+	tm.Set(key, 10, time.Hour)
+
+	v, err := Increment(tm, key, 5)
+	if err != nil || v != 15 {
+		t.Fatalf("Increment returned (%d, %v), want (15, nil)", v, err)
+	}
+
+	v, err = Decrement(tm, key, 3)
+	if err != nil || v != 12 {
+		t.Fatalf("Decrement returned (%d, %v), want (12, nil)", v, err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	tm := New[string, string]()
+	t.Cleanup(tm.Flush)
+
+	tm.Set("keep", "val1", time.Hour)
+	tm.Set("expired", "val2", NoExpiration)
+	tm.container["expired"].expires = time.Now().Add(-time.Hour).UnixNano()
+	tm.container["expired"].noExpire = false
+
+	var buf bytes.Buffer
+	if err := tm.Save(&buf); err != nil {
+		t.Fatalf("Save returned %v", err)
+	}
+
+	loaded := New[string, string]()
+	t.Cleanup(loaded.Flush)
+	loaded.Set("untouched", "val3", time.Hour)
+
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load returned %v", err)
+	}
+
+	if v, ok := loaded.GetValue("keep"); !ok || v != "val1" {
+		t.Fatalf("keep = (%q, %v), want (val1, true)", v, ok)
+	}
+	if _, ok := loaded.GetValue("expired"); ok {
+		t.Fatal("expired entry was loaded")
+	}
+	if _, ok := loaded.GetValue("untouched"); !ok {
+		t.Fatal("Load did not merge, it replaced existing entries")
+	}
+}
+
+func TestLoadReplace(t *testing.T) {
+	tm := New[string, string]()
+	t.Cleanup(tm.Flush)
+	tm.Set("keep", "val1", time.Hour)
+
+	var buf bytes.Buffer
+	if err := tm.Save(&buf); err != nil {
+		t.Fatalf("Save returned %v", err)
+	}
+
+	loaded := New[string, string]()
+	t.Cleanup(loaded.Flush)
+	loaded.Set("untouched", "val2", time.Hour)
+
+	if err := loaded.LoadReplace(&buf); err != nil {
+		t.Fatalf("LoadReplace returned %v", err)
+	}
+
+	if _, ok := loaded.GetValue("untouched"); ok {
+		t.Fatal("LoadReplace did not flush existing entries")
+	}
+	if _, ok := loaded.GetValue("keep"); !ok {
+		t.Fatal("LoadReplace did not load the saved entry")
+	}
+}
+
 func TestSize(t *testing.T) {
 	var tm = newTmap(t)
 